@@ -0,0 +1,89 @@
+package relevantcache
+
+import "sync"
+
+// Helpers for sharding multi-key commands (DEL/UNLINK/MGET) across a Redis
+// Cluster, and for scanning every master node instead of a single instance.
+//
+// Redis Cluster splits the keyspace into 16384 hash slots. A multi-key
+// command whose keys don't all map to the same slot is rejected with
+// CROSSSLOT, so for cluster mode we group keys by slot and issue one
+// command per slot instead of a single command for the whole batch.
+
+const clusterSlotCount = 16384
+
+// crc16Table is the CCITT polynomial table used by Redis Cluster to compute
+// a key's hash slot. See: https://redis.io/docs/reference/cluster-spec/#key-distribution-model
+var crc16Table = func() [256]uint16 {
+	const poly = 0x1021
+	var table [256]uint16
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crc16(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc = (crc << 8) ^ crc16Table[byte(crc>>8)^c]
+	}
+	return crc
+}
+
+// clusterSlot computes the Redis Cluster hash slot for key, honoring the
+// "{hashtag}" convention so related keys can be pinned to the same slot.
+func clusterSlot(key string) uint16 {
+	if start := indexByte(key, '{'); start >= 0 {
+		if end := indexByte(key[start+1:], '}'); end >= 0 && end > 0 {
+			return crc16([]byte(key[start+1:start+1+end])) % clusterSlotCount
+		}
+	}
+	return crc16([]byte(key)) % clusterSlotCount
+}
+
+func indexByte(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// groupBySlot buckets keys by their cluster hash slot so each bucket can be
+// dispatched as a single multi-key command.
+func groupBySlot(keys []string) map[uint16][]string {
+	groups := map[uint16][]string{}
+	for _, k := range keys {
+		slot := clusterSlot(k)
+		groups[slot] = append(groups[slot], k)
+	}
+	return groups
+}
+
+// syncStrings accumulates strings appended concurrently, e.g. by the
+// per-master goroutines redis.ClusterClient.ForEachMaster spawns to run its
+// callback. Appending straight into a shared slice from those goroutines
+// would be a data race; syncStrings guards the slice with a mutex instead.
+type syncStrings struct {
+	mu  sync.Mutex
+	all []string
+}
+
+func (s *syncStrings) append(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.all = append(s.all, keys...)
+	s.mu.Unlock()
+}
@@ -0,0 +1,46 @@
+package relevantcache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestSyncStringsConcurrentAppend exercises the exact access pattern
+// factoryRelevantKeysWithAsterisk and runRelevantKeysScriptForKey rely on:
+// redis.ClusterClient.ForEachMaster invokes its callback once per master
+// concurrently, and every callback appends its share of keys into the same
+// syncStrings. Run with -race; a shared slice appended to without a mutex
+// would be flagged here.
+func TestSyncStringsConcurrentAppend(t *testing.T) {
+	const masters = 8
+	const keysPerMaster = 50
+
+	var merged syncStrings
+	var wg sync.WaitGroup
+	wg.Add(masters)
+	for m := 0; m < masters; m++ {
+		m := m
+		go func() {
+			defer wg.Done()
+			keys := make([]string, keysPerMaster)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("master-%d-key-%d", m, i)
+			}
+			merged.append(keys)
+		}()
+	}
+	wg.Wait()
+
+	if got, want := len(merged.all), masters*keysPerMaster; got != want {
+		t.Fatalf("merged %d keys, want %d", got, want)
+	}
+
+	seen := make(map[string]struct{}, len(merged.all))
+	for _, k := range merged.all {
+		if _, dup := seen[k]; dup {
+			t.Fatalf("key %q appended more than once", k)
+		}
+		seen[k] = struct{}{}
+	}
+}
@@ -0,0 +1,38 @@
+package relevantcache
+
+// Bridge helpers exposing the handful of unexported primitives that other
+// Cache implementations living in subpackages (e.g. relevantcache/memory)
+// need in order to reproduce the exact same relevant-key semantics as
+// RedisCache: the *Item encoding/framing, the string-or-*Item key
+// resolution, and the delimiter used to join relevant keys in the meta
+// header.
+
+// KeyDelimiter is the separator used between relevant key names in an
+// encoded item's meta header.
+const KeyDelimiter = keyDelimiter
+
+// ResolveKey resolves item, which must be either a string or a *Item, to
+// its cache key. It is the same resolution RedisCache uses for every
+// method that accepts `item interface{}`.
+func ResolveKey(item interface{}) (string, error) {
+	return getKey(item)
+}
+
+// DecodeMeta splits an encoded value back into its relevant-keys header
+// and the original stored payload, exactly as RedisCache does on Get/MGet.
+func DecodeMeta(b []byte) (relevant []byte, data []byte) {
+	return decodeMeta(b)
+}
+
+// EncodeItem extracts the cache key, encoded value (meta header + payload)
+// and TTL (in seconds, 0 meaning no expiration) from item, ready to be
+// stored verbatim by any Cache implementation.
+func EncodeItem(item *Item) (key string, value []byte, ttl int64) {
+	return item.cacheKey(), item.encode(), item.ttl
+}
+
+// RelevantKeysOf returns the relevant key names item.Set was told depend on
+// it, the same list factoryRelevantKeys walks for RedisCache.
+func RelevantKeysOf(item *Item) []string {
+	return item.getRelevaneKeys()
+}
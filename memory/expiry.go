@@ -0,0 +1,27 @@
+package memory
+
+import "time"
+
+// expiryEntry pairs a key with the time it should be evicted at, and is the
+// element type of expiryHeap.
+type expiryEntry struct {
+	key string
+	at  time.Time
+}
+
+// expiryHeap is a container/heap min-heap ordered by expiry time, used by
+// InMemoryCache's janitor goroutine to find the next key due for eviction
+// without scanning the whole keyspace.
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int            { return len(h) }
+func (h expiryHeap) Less(i, j int) bool  { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(*expiryEntry)) }
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,312 @@
+// Package memory provides an in-process Cache implementation that
+// reproduces relevantcache's relevant-key invalidation semantics without
+// requiring a Redis server. It is primarily useful for unit tests and
+// single-node deployments.
+package memory
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ysugimoto/relevantcache"
+)
+
+// record is a single stored value plus the relevant keys it depends on, so
+// Del/Unlink can walk the same dependency graph factoryRelevantKeys does
+// against Redis.
+type record struct {
+	value    []byte
+	relevant []string
+	expires  time.Time // zero value means no expiration
+}
+
+func (r *record) expired(now time.Time) bool {
+	return !r.expires.IsZero() && !now.Before(r.expires)
+}
+
+// InMemoryCache is a Cache implementation backed by a map guarded by a
+// mutex, with a min-heap driving background TTL expiry so expired entries
+// don't linger until they're next read.
+type InMemoryCache struct {
+	mu     sync.Mutex
+	data   map[string]*record
+	hashes map[string]map[string][]byte
+	expiry *expiryHeap
+	close  chan struct{}
+}
+
+// New creates an InMemoryCache and starts its background TTL janitor.
+// Call Close to stop the janitor goroutine.
+func New() *InMemoryCache {
+	c := &InMemoryCache{
+		data:   map[string]*record{},
+		hashes: map[string]map[string][]byte{},
+		expiry: &expiryHeap{},
+		close:  make(chan struct{}),
+	}
+	heap.Init(c.expiry)
+	go c.janitor()
+	return c
+}
+
+func (c *InMemoryCache) janitor() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.close:
+			return
+		case <-ticker.C:
+			c.sweep()
+		}
+	}
+}
+
+func (c *InMemoryCache) sweep() {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.expiry.Len() > 0 {
+		next := (*c.expiry)[0]
+		if next.at.After(now) {
+			return
+		}
+		heap.Pop(c.expiry)
+		if r, ok := c.data[next.key]; ok && r.expired(now) {
+			delete(c.data, next.key)
+		}
+	}
+}
+
+// Close stops the background TTL janitor.
+func (c *InMemoryCache) Close() error {
+	close(c.close)
+	return nil
+}
+
+// Purge drops every stored key and hash.
+func (c *InMemoryCache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = map[string]*record{}
+	c.hashes = map[string]map[string][]byte{}
+	c.expiry = &expiryHeap{}
+	return nil
+}
+
+func (c *InMemoryCache) Increment(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.data[key]
+	if !ok || r.expired(time.Now()) {
+		r = &record{value: []byte("0")}
+		c.data[key] = r
+	}
+	n := int64(0)
+	fmt.Sscanf(string(r.value), "%d", &n)
+	r.value = []byte(fmt.Sprintf("%d", n+1))
+	return nil
+}
+
+// Get mirrors RedisCache.Get: item is acceptable as either a string or a
+// *relevantcache.Item.
+func (c *InMemoryCache) Get(item interface{}) ([]byte, error) {
+	key, err := relevantcache.ResolveKey(item)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.data[key]
+	if !ok || r.expired(time.Now()) {
+		return nil, relevantcache.RedisNil
+	}
+	_, data := relevantcache.DecodeMeta(r.value)
+	return data, nil
+}
+
+// Set mirrors RedisCache.Set: it accepts a *relevantcache.Item, or a
+// (key, value) / (key, value, ttl) triple.
+func (c *InMemoryCache) Set(args ...interface{}) error {
+	var key string
+	var value []byte
+	var relevant []string
+	var ttl int64
+
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("argments not enough")
+	case 1:
+		item, ok := args[0].(*relevantcache.Item)
+		if !ok {
+			return fmt.Errorf("if and only one argument is supplied, it must be *Item")
+		}
+		var encoded []byte
+		key, encoded, ttl = relevantcache.EncodeItem(item)
+		value = encoded
+		relevant = relevantcache.RelevantKeysOf(item)
+	case 2:
+		key = args[0].(string)
+		value = toBytes(args[1])
+	case 3:
+		key = args[0].(string)
+		value = toBytes(args[1])
+		ttl = int64(args[2].(int))
+	default:
+		return fmt.Errorf("argments not enough")
+	}
+
+	r := &record{value: value, relevant: relevant}
+	if ttl > 0 {
+		r.expires = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = r
+	if !r.expires.IsZero() {
+		heap.Push(c.expiry, &expiryEntry{key: key, at: r.expires})
+	}
+	return nil
+}
+
+func toBytes(v interface{}) []byte {
+	switch t := v.(type) {
+	case []byte:
+		return t
+	case string:
+		return []byte(t)
+	default:
+		return []byte(fmt.Sprintf("%v", t))
+	}
+}
+
+// Del removes items and everything transitively relevant to them.
+func (c *InMemoryCache) Del(items ...interface{}) error {
+	return c.delete(items...)
+}
+
+// Unlink behaves identically to Del; there's no separate "non-blocking"
+// reclamation path for an in-process map.
+func (c *InMemoryCache) Unlink(items ...interface{}) error {
+	return c.delete(items...)
+}
+
+func (c *InMemoryCache) delete(items ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := map[string]struct{}{}
+	for _, v := range items {
+		key, err := relevantcache.ResolveKey(v)
+		if err != nil {
+			continue
+		}
+		for _, k := range c.relevantKeysLocked(key) {
+			keys[k] = struct{}{}
+		}
+	}
+	for k := range keys {
+		delete(c.data, k)
+	}
+	return nil
+}
+
+// relevantKeysLocked walks the dependency graph the same way
+// RedisCache.factoryRelevantKeys does: the key itself, plus every key
+// listed in its stored meta, recursively. The caller must hold c.mu.
+func (c *InMemoryCache) relevantKeysLocked(key string) []string {
+	if containsAsterisk(key) {
+		return c.relevantKeysWithAsteriskLocked(key)
+	}
+
+	r, ok := c.data[key]
+	if !ok || r.expired(time.Now()) {
+		return nil
+	}
+	relevantKeys := []string{key}
+	for _, v := range r.relevant {
+		relevantKeys = append(relevantKeys, c.relevantKeysLocked(v)...)
+	}
+	return relevantKeys
+}
+
+func (c *InMemoryCache) relevantKeysWithAsteriskLocked(pattern string) []string {
+	relevantKeys := []string{}
+	for k := range c.data {
+		matched, err := filepath.Match(pattern, k)
+		if err != nil || !matched {
+			continue
+		}
+		relevantKeys = append(relevantKeys, c.relevantKeysLocked(k)...)
+	}
+	return relevantKeys
+}
+
+func containsAsterisk(key string) bool {
+	return bytes.ContainsRune([]byte(key), '*')
+}
+
+func (c *InMemoryCache) MGet(keys ...interface{}) ([][]byte, error) {
+	ret := make([][]byte, len(keys))
+	for i, k := range keys {
+		key, err := relevantcache.ResolveKey(k)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		r, ok := c.data[key]
+		if ok && !r.expired(time.Now()) {
+			_, data := relevantcache.DecodeMeta(r.value)
+			ret[i] = data
+		}
+		c.mu.Unlock()
+	}
+	return ret, nil
+}
+
+func (c *InMemoryCache) HSet(key interface{}, field string, value interface{}) error {
+	k, err := relevantcache.ResolveKey(key)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hashes[k]
+	if !ok {
+		h = map[string][]byte{}
+		c.hashes[k] = h
+	}
+	h[field] = toBytes(value)
+	return nil
+}
+
+func (c *InMemoryCache) HGet(key interface{}, field string) ([]byte, error) {
+	k, err := relevantcache.ResolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.hashes[k][field]
+	if !ok {
+		return nil, relevantcache.RedisNil
+	}
+	return v, nil
+}
+
+func (c *InMemoryCache) HLen(key interface{}) (int64, error) {
+	k, err := relevantcache.ResolveKey(key)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(len(c.hashes[k])), nil
+}
+
+var _ relevantcache.Cache = (*InMemoryCache)(nil)
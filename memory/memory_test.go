@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ysugimoto/relevantcache"
+)
+
+// TestInMemoryCacheItemRoundTrip pins the dominant *Item call pattern used
+// throughout the rest of the module: Set stores the relevant-keys meta
+// header alongside the payload, and Get/MGet must strip it back off again,
+// exactly like RedisCache.Get/mget do via decodeMeta.
+func TestInMemoryCacheItemRoundTrip(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	item := relevantcache.NewItem("k1", []byte("hello"), 0, []string{"parent:1"})
+	if err := c.Set(item); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+
+	got, err := c.Get("k1")
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if !bytes.Equal(got, []byte("hello")) {
+		t.Fatalf("Get(%q) = %q, want %q (meta header leaked)", "k1", got, "hello")
+	}
+
+	results, err := c.MGet("k1")
+	if err != nil {
+		t.Fatalf("MGet: %s", err)
+	}
+	if len(results) != 1 || !bytes.Equal(results[0], []byte("hello")) {
+		t.Fatalf("MGet(%q) = %q, want [%q] (meta header leaked)", "k1", results, "hello")
+	}
+}
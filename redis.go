@@ -2,6 +2,7 @@ package relevantcache
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -10,38 +11,48 @@ import (
 	"crypto/tls"
 	"net/url"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
 var RedisNil = redis.Nil
 
-// Redis backend struct
+// Redis backend struct.
+// conn is a redis.UniversalClient so a RedisCache can be backed by a plain
+// standalone client, a Sentinel-managed failover client, or a Cluster
+// client without the rest of the implementation needing to know which.
+//
+// ctx is the context every redis call is issued with. It defaults to
+// context.Background() and is only ever replaced via WithContext, so the
+// exported methods below keep their original (ctx-less) signatures for
+// backward compatibility while still supporting cancellation, deadlines
+// and tracing for callers that opt in.
 type RedisCache struct {
-	conn *redis.Client
-	w    io.Writer
+	conn                redis.UniversalClient
+	cluster             bool
+	w                   io.Writer
+	ctx                 context.Context
+	invalidationChannel string
 }
 
-func (r *RedisCache) Redis() *redis.Client {
+func (r *RedisCache) Redis() redis.UniversalClient {
 	return r.conn
 }
 
+// WithContext returns a shallow copy of the cache bound to ctx. Use it to
+// thread a request-scoped deadline or tracing span through a call, e.g.
+// rc.WithContext(ctx).Del(item). The receiver is left untouched.
+func (r *RedisCache) WithContext(ctx context.Context) *RedisCache {
+	clone := *r
+	clone.ctx = ctx
+	return &clone
+}
+
 // Create RedisCache pointer with some options
 // Currently enabled options are:
 //
 // rc.WithSkipTLSVerify(bool): Skip TLS verification
 func NewRedisCache(endpoint string, opts ...option) (*RedisCache, error) {
-	var skipVerify bool
-	var w io.Writer
-	for _, o := range opts {
-		switch o.name {
-		case optionNameSkipTLSVerify:
-			skipVerify = o.value.(bool)
-			// case optionNameSplitBufferSize:
-			// 	splitChunkSize = o.value.(int64)
-		case optionNameDebugWriter:
-			w = o.value.(io.Writer)
-		}
-	}
+	_, skipVerify, w, invalidationChannel := parseOptions(opts)
 
 	u, err := url.Parse(endpoint)
 	if err != nil {
@@ -51,27 +62,135 @@ func NewRedisCache(endpoint string, opts ...option) (*RedisCache, error) {
 		Addr: u.Host,
 	}
 	if u.Scheme == tlsProtocol {
-		hp := strings.SplitN(u.Host, ":", 2)
-		options.TLSConfig = &tls.Config{
-			ServerName:         hp[0],
-			InsecureSkipVerify: false,
-		}
-		if skipVerify {
-			options.TLSConfig.InsecureSkipVerify = true
-		}
+		applyTLS(&options.TLSConfig, u.Host, skipVerify)
 	}
 	conn := redis.NewClient(options)
-	if pong, err := conn.Ping().Result(); err != nil {
+	ctx := context.Background()
+	if err := ping(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &RedisCache{
+		conn:                conn,
+		w:                   w,
+		ctx:                 ctx,
+		invalidationChannel: invalidationChannel,
+	}, nil
+}
+
+// NewRedisSentinelCache builds a RedisCache backed by a Sentinel-managed
+// failover client. masterName must match the name Sentinel was configured
+// with, and sentinelAddrs is the list of Sentinel instances to discover the
+// current master/replicas through. Pass WithTLS() to connect over TLS, and
+// additionally WithSkipTLSVerify(true) to skip certificate verification.
+func NewRedisSentinelCache(masterName string, sentinelAddrs []string, opts ...option) (*RedisCache, error) {
+	useTLS, skipVerify, w, invalidationChannel := parseOptions(opts)
+
+	options := &redis.UniversalOptions{
+		MasterName: masterName,
+		Addrs:      sentinelAddrs,
+	}
+	if useTLS || skipVerify {
+		applyTLS(&options.TLSConfig, "", skipVerify)
+	}
+	conn := redis.NewUniversalClient(options)
+	ctx := context.Background()
+	if err := ping(ctx, conn); err != nil {
 		return nil, err
-	} else if pong != "PONG" {
-		return nil, fmt.Errorf("failed to receive PONG from server")
 	}
 	return &RedisCache{
-		conn: conn,
-		w:    w,
+		conn:                conn,
+		w:                   w,
+		ctx:                 ctx,
+		invalidationChannel: invalidationChannel,
 	}, nil
 }
 
+// NewRedisClusterCache builds a RedisCache backed by a Redis Cluster client.
+// addrs is the list of cluster shard endpoints; the client discovers the
+// rest of the topology from the cluster itself.
+//
+// Because cluster mode requires multi-key commands to target a single hash
+// slot, Del/Unlink/MGet transparently shard their keys per-slot before
+// dispatching, and SCAN walks every master node instead of a single
+// instance. See factoryRelevantKeysWithAsterisk.
+//
+// Pass WithTLS() to connect over TLS, and additionally
+// WithSkipTLSVerify(true) to skip certificate verification.
+func NewRedisClusterCache(addrs []string, opts ...option) (*RedisCache, error) {
+	useTLS, skipVerify, w, invalidationChannel := parseOptions(opts)
+
+	options := &redis.ClusterOptions{
+		Addrs: addrs,
+	}
+	if useTLS || skipVerify {
+		applyTLS(&options.TLSConfig, "", skipVerify)
+	}
+	conn := redis.NewClusterClient(options)
+	ctx := context.Background()
+	if err := ping(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &RedisCache{
+		conn:                conn,
+		cluster:             true,
+		w:                   w,
+		ctx:                 ctx,
+		invalidationChannel: invalidationChannel,
+	}, nil
+}
+
+// optionNameTLS selects WithTLS, which enables TLS on Sentinel/Cluster
+// connections (NewRedisCache infers TLS from its endpoint's URL scheme
+// instead) independently of whether certificate verification is skipped.
+const optionNameTLS = "tls"
+
+// WithTLS enables TLS on a NewRedisSentinelCache/NewRedisClusterCache
+// connection. Unlike WithSkipTLSVerify, which only controls whether the
+// server certificate is verified, WithTLS is what actually turns TLS on;
+// combine the two to get a secure, certificate-verified connection, since
+// WithSkipTLSVerify alone no longer implies TLS.
+func WithTLS() option {
+	return option{name: optionNameTLS, value: true}
+}
+
+func parseOptions(opts []option) (useTLS, skipVerify bool, w io.Writer, invalidationChannel string) {
+	for _, o := range opts {
+		switch o.name {
+		case optionNameTLS:
+			useTLS = o.value.(bool)
+		case optionNameSkipTLSVerify:
+			skipVerify = o.value.(bool)
+			// case optionNameSplitBufferSize:
+			// 	splitChunkSize = o.value.(int64)
+		case optionNameDebugWriter:
+			w = o.value.(io.Writer)
+		case optionNameInvalidationChannel:
+			invalidationChannel = o.value.(string)
+		}
+	}
+	return
+}
+
+func applyTLS(conf **tls.Config, host string, skipVerify bool) {
+	var serverName string
+	if host != "" {
+		serverName = strings.SplitN(host, ":", 2)[0]
+	}
+	*conf = &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: skipVerify,
+	}
+}
+
+func ping(ctx context.Context, conn redis.UniversalClient) error {
+	if pong, err := conn.Ping(ctx).Result(); err != nil {
+		return err
+	} else if pong != "PONG" {
+		return fmt.Errorf("failed to receive PONG from server")
+	}
+	return nil
+}
+
 // Close connection
 func (r *RedisCache) Close() error {
 	return r.conn.Close()
@@ -79,11 +198,11 @@ func (r *RedisCache) Close() error {
 
 // Purge all caches
 func (r *RedisCache) Purge() error {
-	return r.conn.FlushDBAsync().Err()
+	return r.conn.FlushDBAsync(r.ctx).Err()
 }
 
 func (r *RedisCache) Increment(key string) error {
-	return r.conn.Incr(key).Err()
+	return r.conn.Incr(r.ctx, key).Err()
 }
 
 // Wrap of redis.GET
@@ -93,7 +212,7 @@ func (r *RedisCache) Get(item interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	b, err := r.conn.Get(key).Bytes()
+	b, err := r.conn.Get(r.ctx, key).Bytes()
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +222,7 @@ func (r *RedisCache) Get(item interface{}) ([]byte, error) {
 }
 
 func (r *RedisCache) Dump() string {
-	keys, _ := r.conn.Keys("*").Result()
+	keys, _ := r.conn.Keys(r.ctx, "*").Result()
 	return fmt.Sprintf("%q", keys)
 }
 
@@ -144,7 +263,7 @@ func (r *RedisCache) Set(args ...interface{}) (err error) {
 	if ttl > 0 {
 		expire = time.Duration(ttl) * time.Second
 	}
-	return r.conn.Set(key, value, expire).Err()
+	return r.conn.Set(r.ctx, key, value, expire).Err()
 }
 
 // Wrap of redis.DEL
@@ -157,7 +276,13 @@ func (r *RedisCache) Del(items ...interface{}) error {
 	}
 
 	debug(r.w, fmt.Sprintf("[DEL] delete relevant caches %q\n", keys))
-	return r.conn.Del(keys...).Err()
+	if err := r.dispatchMultiKey(keys, func(k ...string) error {
+		return r.conn.Del(r.ctx, k...).Err()
+	}); err != nil {
+		return err
+	}
+	r.publishInvalidation(keys)
+	return nil
 }
 
 // Wrap of redis.UNLINK, note that ensure your redis engine is later than v4
@@ -170,7 +295,36 @@ func (r *RedisCache) Unlink(items ...interface{}) error {
 	}
 
 	debug(r.w, fmt.Sprintf("[UNLINK] delete relevant caches %q\n", keys))
-	return r.conn.Unlink(keys...).Err()
+	if err := r.dispatchMultiKey(keys, func(k ...string) error {
+		return r.conn.Unlink(r.ctx, k...).Err()
+	}); err != nil {
+		return err
+	}
+	r.publishInvalidation(keys)
+	return nil
+}
+
+// dispatchMultiKey issues fn once with all keys when not running against a
+// cluster, or once per hash-slot group when it is, since Redis Cluster
+// rejects a multi-key command whose keys don't all land on the same slot.
+func (r *RedisCache) dispatchMultiKey(keys []string, fn func(...string) error) error {
+	if !r.cluster {
+		return fn(keys...)
+	}
+	for _, group := range groupBySlot(keys) {
+		if err := fn(group...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelevantKeys resolves the full set of keys relevant to items, i.e. the
+// same set Del/Unlink would remove. It's exposed so wrapper caches (such as
+// tiered.TieredCache) can mirror an invalidation against their own local
+// state without duplicating the meta-graph walk.
+func (r *RedisCache) RelevantKeys(items ...interface{}) []string {
+	return r.factoryDeleteKeys("REL", items...)
 }
 
 func (r *RedisCache) factoryDeleteKeys(method string, keys ...interface{}) []string {
@@ -203,7 +357,7 @@ func (r *RedisCache) factoryRelevantKeys(key string) []string {
 	}
 
 	relevantKeys := []string{}
-	b, err := r.conn.Get(key).Bytes()
+	b, err := r.conn.Get(r.ctx, key).Bytes()
 	if err != nil {
 		debug(r.w, fmt.Sprintf("failed to get record for delete. Key is %v, %s\n", key, err.Error()))
 		return relevantKeys
@@ -225,11 +379,35 @@ func (r *RedisCache) factoryRelevantKeys(key string) []string {
 
 // Dealing asterisk sign
 func (r *RedisCache) factoryRelevantKeysWithAsterisk(key string) []string {
+	// In cluster mode the keyspace is sharded across masters, so a single
+	// SCAN only ever sees the node it was issued against. Walk every master
+	// instead of a single connection.
+	//
+	// ForEachMaster runs its callback concurrently, one goroutine per
+	// master, so each call collects into its own slice and the results are
+	// merged once every goroutine has returned; appending straight into a
+	// shared slice here would be a data race.
+	if cluster, ok := r.conn.(*redis.ClusterClient); ok {
+		var merged syncStrings
+		cluster.ForEachMaster(r.ctx, func(ctx context.Context, c *redis.Client) error {
+			merged.append(r.scanRelevantKeys(c, key))
+			return nil
+		})
+		debug(r.w, fmt.Sprintf("[REL-ASTERISK] %s is relevant to %q\n", key, merged.all))
+		return merged.all
+	}
+
+	relevantKeys := r.scanRelevantKeys(r.conn, key)
+	debug(r.w, fmt.Sprintf("[REL-ASTERISK] %s is relevant to %q\n", key, relevantKeys))
+	return relevantKeys
+}
+
+func (r *RedisCache) scanRelevantKeys(conn redis.UniversalClient, key string) []string {
 	relevantKeys := []string{}
 	cursor := uint64(0)
 	count := int64(1000)
 	for {
-		keys, c, err := r.conn.Scan(cursor, key, count).Result()
+		keys, c, err := conn.Scan(r.ctx, cursor, key, count).Result()
 		if err != nil {
 			debug(r.w, fmt.Sprintf("failed to scan keys for %s, %s\n", key, err.Error()))
 			return relevantKeys
@@ -243,7 +421,6 @@ func (r *RedisCache) factoryRelevantKeysWithAsterisk(key string) []string {
 		}
 		cursor = c
 	}
-	debug(r.w, fmt.Sprintf("[REL-ASTERISK] %s is relevant to %q\n", key, relevantKeys))
 	return relevantKeys
 }
 
@@ -256,7 +433,35 @@ func (r *RedisCache) MGet(keys ...interface{}) ([][]byte, error) {
 		}
 		cacheKeys[i] = key
 	}
-	result, err := r.conn.MGet(cacheKeys...).Result()
+
+	if !r.cluster {
+		return r.mget(r.conn, cacheKeys)
+	}
+
+	// MGET across arbitrary keys needs per-slot sharding in cluster mode;
+	// fetch each slot group separately and stitch the results back together
+	// in the caller's original order.
+	ret := make([][]byte, len(cacheKeys))
+	index := make(map[string][]int, len(cacheKeys))
+	for i, k := range cacheKeys {
+		index[k] = append(index[k], i)
+	}
+	for _, group := range groupBySlot(cacheKeys) {
+		values, err := r.mget(r.conn, group)
+		if err != nil {
+			return nil, err
+		}
+		for i, k := range group {
+			for _, pos := range index[k] {
+				ret[pos] = values[i]
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (r *RedisCache) mget(conn redis.UniversalClient, cacheKeys []string) ([][]byte, error) {
+	result, err := conn.MGet(r.ctx, cacheKeys...).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -279,7 +484,7 @@ func (r *RedisCache) HSet(key interface{}, field string, value interface{}) erro
 	if err != nil {
 		return err
 	}
-	if err := r.conn.HSet(k, field, value).Err(); err != nil {
+	if err := r.conn.HSet(r.ctx, k, field, value).Err(); err != nil {
 		fmt.Println(err)
 		return err
 	}
@@ -291,7 +496,7 @@ func (r *RedisCache) HLen(key interface{}) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	size, err := r.conn.HLen(k).Result()
+	size, err := r.conn.HLen(r.ctx, k).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -303,7 +508,7 @@ func (r *RedisCache) HGet(key interface{}, field string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	v, err := r.conn.HGet(k, field).Bytes()
+	v, err := r.conn.HGet(r.ctx, k, field).Bytes()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,184 @@
+package relevantcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// relevantKeysLua walks the relevant-key DAG entirely on the server instead
+// of one GET round trip per node: given a starting key it GETs the value,
+// parses the same header framing decodeMeta uses in Go (relevant keys
+// joined by KeyDelimiter, terminated by a newline, followed by the raw
+// payload), pushes each relevant key onto a work stack, dedupes via a Lua
+// table keyed by name, and expands any "*"-containing entry with SCAN.
+// With ARGV[2] == "1" it also runs ARGV[3] (DEL or UNLINK) against every
+// resolved key before returning, making the whole walk-and-delete atomic
+// against concurrent writers.
+const relevantKeysLua = `
+local delimiter = ARGV[1]
+local eager = ARGV[2] == "1"
+local command = ARGV[3]
+local seen = {}
+local stack = {KEYS[1]}
+local result = {}
+
+local function expand(pattern)
+  local cursor = "0"
+  repeat
+    local res = redis.call("SCAN", cursor, "MATCH", pattern, "COUNT", 1000)
+    cursor = res[1]
+    for _, k in ipairs(res[2]) do
+      table.insert(stack, k)
+    end
+  until cursor == "0"
+end
+
+while #stack > 0 do
+  local key = table.remove(stack)
+  if not seen[key] then
+    seen[key] = true
+    if string.find(key, "*", 1, true) then
+      expand(key)
+    else
+      local val = redis.call("GET", key)
+      if val then
+        table.insert(result, key)
+        local nl = string.find(val, "\n", 1, true)
+        if nl then
+          local header = string.sub(val, 1, nl - 1)
+          if header ~= "" then
+            for part in string.gmatch(header, "([^" .. delimiter .. "]+)") do
+              table.insert(stack, part)
+            end
+          end
+        end
+      end
+    end
+  end
+end
+
+if eager and #result > 0 then
+  redis.call(command, unpack(result))
+end
+
+return result
+`
+
+var relevantKeysScript = redis.NewScript(relevantKeysLua)
+
+// RelevantKeysAtomic resolves the full relevant-key set for each of items
+// in a single round trip per item, the same set RelevantKeys/Del/Unlink
+// compute by walking the graph one GET at a time in Go.
+func (r *RedisCache) RelevantKeysAtomic(items ...interface{}) ([]string, error) {
+	return r.runRelevantKeysScript(items, "", false)
+}
+
+// DelAtomic resolves and DELs the full relevant-key set for each of items
+// in one round trip per item, instead of the N round-trip walk Del does in
+// Go followed by a separate DEL. Because the walk and the delete happen
+// inside one Lua script, concurrent writers can't observe a
+// partially-invalidated state.
+//
+// In cluster mode a script can only touch keys that hash to the slot of
+// its first KEYS entry, so each item's walk is pinned to that item's own
+// slot rather than fanned out across the cluster; if the walk discovers a
+// relevant key that lands in a different slot, Redis returns a CROSSSLOT
+// error. Pin relevant keys to the same hash tag as their parent if this
+// needs to work reliably against a cluster, or fall back to the plain
+// Del/Unlink, which already shard per item. A wildcard item is handled
+// separately: see runRelevantKeysScript.
+func (r *RedisCache) DelAtomic(items ...interface{}) error {
+	_, err := r.runRelevantKeysScript(items, "DEL", true)
+	return err
+}
+
+// UnlinkAtomic is DelAtomic's non-blocking-reclamation sibling.
+func (r *RedisCache) UnlinkAtomic(items ...interface{}) error {
+	_, err := r.runRelevantKeysScript(items, "UNLINK", true)
+	return err
+}
+
+func (r *RedisCache) runRelevantKeysScript(items []interface{}, command string, eager bool) ([]string, error) {
+	eagerArg := "0"
+	if eager {
+		eagerArg = "1"
+	}
+
+	seen := map[string]struct{}{}
+	all := []string{}
+	for _, item := range items {
+		key, err := getKey(item)
+		if err != nil {
+			debug(r.w, fmt.Sprintf("[ATOMIC] invalid key: %v, %s\n", item, err.Error()))
+			continue
+		}
+
+		list, err := r.runRelevantKeysScriptForKey(key, eagerArg, command)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range list {
+			if _, dup := seen[s]; dup {
+				continue
+			}
+			seen[s] = struct{}{}
+			all = append(all, s)
+		}
+	}
+
+	debug(r.w, fmt.Sprintf("[ATOMIC] %s resolved keys %q\n", command, all))
+	return all, nil
+}
+
+// runRelevantKeysScriptForKey runs relevantKeysScript for a single key.
+//
+// A wildcard key's in-script SCAN only sees the keyspace of whichever node
+// the script happens to run on, exactly like factoryRelevantKeysWithAsterisk
+// for the non-atomic path. So in cluster mode a wildcard key is fanned out
+// and run once per master instead of once against whichever single node it
+// hashes to, which would otherwise silently under-invalidate every other
+// master's matches.
+func (r *RedisCache) runRelevantKeysScriptForKey(key, eagerArg, command string) ([]string, error) {
+	cluster, ok := r.conn.(*redis.ClusterClient)
+	if !ok || !strings.Contains(key, "*") {
+		// Script.Run caches the SHA after its first EVALSHA and
+		// transparently falls back to EVAL (re-caching the SHA) on
+		// NOSCRIPT, so repeated calls cost one round trip each.
+		res, err := relevantKeysScript.Run(r.ctx, r.conn, []string{key}, keyDelimiter, eagerArg, command).Result()
+		if err != nil {
+			return nil, err
+		}
+		return resultToStrings(res), nil
+	}
+
+	var merged syncStrings
+	err := cluster.ForEachMaster(r.ctx, func(ctx context.Context, c *redis.Client) error {
+		res, err := relevantKeysScript.Run(ctx, c, []string{key}, keyDelimiter, eagerArg, command).Result()
+		if err != nil {
+			return err
+		}
+		merged.append(resultToStrings(res))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged.all, nil
+}
+
+func resultToStrings(res interface{}) []string {
+	list, ok := res.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
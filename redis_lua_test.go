@@ -0,0 +1,70 @@
+package relevantcache
+
+import (
+	"strings"
+	"testing"
+)
+
+// luaParseHeader ports the header-extraction step of relevantKeysLua (see
+// redis_lua.go) to Go: find the first newline, everything before it is the
+// relevant-keys header. It exists only so this test can pin that Lua logic
+// against decodeMeta without a running Redis server.
+func luaParseHeader(val []byte) (header string, ok bool) {
+	s := string(val)
+	nl := strings.IndexByte(s, '\n')
+	if nl < 0 {
+		return "", false
+	}
+	return s[:nl], true
+}
+
+// TestRelevantKeysLuaFramingMatchesDecodeMeta pins the newline-terminated
+// header framing relevantKeysLua parses in-script to the framing decodeMeta
+// parses in Go, so the two can't silently drift apart.
+func TestRelevantKeysLuaFramingMatchesDecodeMeta(t *testing.T) {
+	cases := []struct {
+		name     string
+		relevant []string
+		payload  []byte
+	}{
+		{"no relevant keys", nil, []byte("payload-a")},
+		{"single relevant key", []string{"parent:1"}, []byte("payload-b")},
+		{"multiple relevant keys", []string{"parent:1", "parent:2", "parent:3"}, []byte("payload-c")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			header := strings.Join(c.relevant, keyDelimiter)
+			encoded := append([]byte(header+"\n"), c.payload...)
+
+			gotRelevant, gotData := decodeMeta(encoded)
+			if string(gotData) != string(c.payload) {
+				t.Fatalf("decodeMeta payload = %q, want %q", gotData, c.payload)
+			}
+			if string(gotRelevant) != header {
+				t.Fatalf("decodeMeta relevant = %q, want %q", gotRelevant, header)
+			}
+
+			luaHeader, ok := luaParseHeader(encoded)
+			if !ok {
+				t.Fatalf("luaParseHeader found no header in %q", encoded)
+			}
+			if luaHeader != string(gotRelevant) {
+				t.Fatalf("lua header = %q, decodeMeta header = %q: framing drifted", luaHeader, gotRelevant)
+			}
+
+			var luaParts []string
+			if luaHeader != "" {
+				luaParts = strings.Split(luaHeader, keyDelimiter)
+			}
+			if len(luaParts) != len(c.relevant) {
+				t.Fatalf("lua header parts = %q, want %q", luaParts, c.relevant)
+			}
+			for i, part := range luaParts {
+				if part != c.relevant[i] {
+					t.Fatalf("lua header parts = %q, want %q", luaParts, c.relevant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package relevantcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// optionNameInvalidationChannel selects the channel WithInvalidationChannel
+// publishes resolved Del/Unlink keys onto.
+const optionNameInvalidationChannel = "invalidation_channel"
+
+// WithInvalidationChannel makes Del/Unlink publish the full relevant-key
+// set they resolve and delete onto channel, so every other RedisCache
+// instance listening via Subscribe (or any ad-hoc Redis SUBSCRIBE) can
+// mirror the invalidation against its own local state -- an in-process
+// cache, a warmed lookup table, metrics, etc.
+func WithInvalidationChannel(channel string) option {
+	return option{name: optionNameInvalidationChannel, value: channel}
+}
+
+// InvalidationHandler receives the set of keys that were invalidated,
+// whether that's the resolved key list from WithInvalidationChannel or a
+// single key reported by a keyspace notification.
+type InvalidationHandler func(keys []string)
+
+// Subscribe listens for invalidations and calls handler with the key(s)
+// involved each time one arrives. It listens on two sources at once:
+//
+//   - the channel configured via WithInvalidationChannel, carrying the
+//     full relevant-key set this library resolved for a Del/Unlink call.
+//   - Redis' built-in keyspace notifications
+//     (__keyevent@<db>__:del / :unlink / :expired), so writers that
+//     bypass this library entirely -- another process, redis-cli, TTL
+//     expiry -- still trigger local invalidation. This requires the
+//     server to have notify-keyspace-events configured with at least
+//     "Kgx$" (or similar; see the Redis docs for the notification
+//     classes you need).
+//
+// Subscribe blocks until ctx is cancelled or the subscription's underlying
+// connection is closed, so callers typically run it in its own goroutine.
+func (r *RedisCache) Subscribe(ctx context.Context, handler InvalidationHandler) error {
+	patterns := []string{
+		"__keyevent@*__:del",
+		"__keyevent@*__:unlink",
+		"__keyevent@*__:expired",
+	}
+
+	sub := r.conn.PSubscribe(ctx, patterns...)
+	defer sub.Close()
+
+	if r.invalidationChannel != "" {
+		if err := sub.Subscribe(ctx, r.invalidationChannel); err != nil {
+			return err
+		}
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if msg.Channel == r.invalidationChannel {
+				handler(strings.Split(msg.Payload, keyDelimiter))
+			} else {
+				// Keyspace notification payload is the key name itself.
+				handler([]string{msg.Payload})
+			}
+		}
+	}
+}
+
+// publishInvalidation publishes keys onto the configured invalidation
+// channel, if any. It's best-effort: a publish failure is logged but never
+// fails the Del/Unlink call that triggered it, since the deletion itself
+// already succeeded.
+func (r *RedisCache) publishInvalidation(keys []string) {
+	if r.invalidationChannel == "" || len(keys) == 0 {
+		return
+	}
+	payload := strings.Join(keys, keyDelimiter)
+	if err := r.conn.Publish(r.ctx, r.invalidationChannel, payload).Err(); err != nil {
+		debug(r.w, fmt.Sprintf("[PUBLISH] failed to publish invalidation on %s: %s\n", r.invalidationChannel, err.Error()))
+	}
+}
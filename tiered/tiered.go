@@ -0,0 +1,258 @@
+// Package tiered provides a two-level Cache that fronts Redis with an
+// in-process LRU, trading a small amount of staleness risk for the
+// read-latency win of not hitting the network on every Get.
+//
+// To keep the L1 layer correct across multiple instances of an
+// application, TieredCache relies on l2's own invalidation facility
+// (relevantcache.WithInvalidationChannel and RedisCache.Subscribe) instead
+// of rolling its own pub/sub: construct l2 with
+// relevantcache.WithInvalidationChannel(...) and every TieredCache wrapping
+// it evicts the matching keys from its own L1 when another instance's
+// Del/Unlink (or a keyspace notification) is observed.
+package tiered
+
+import (
+	"context"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ysugimoto/relevantcache"
+)
+
+// TieredCache wraps an in-process LRU (L1) in front of a RedisCache (L2).
+// Get checks L1 first, falling back to and populating from L2. Set writes
+// through to both. Del/Unlink invalidate the resolved relevant-key set on
+// both, and rely on l2.Subscribe to evict those same keys from every other
+// instance's L1.
+type TieredCache struct {
+	l1     *lru.Cache
+	l2     *relevantcache.RedisCache
+	l1TTL  time.Duration
+	cancel context.CancelFunc
+}
+
+type l1Entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Option configures a TieredCache at construction time.
+type Option func(*TieredCache)
+
+// New builds a TieredCache with an L1 of up to l1Size entries, each held
+// for at most l1TTL (0 means no local expiration beyond L2's own TTL), in
+// front of l2. Construct l2 with relevantcache.WithInvalidationChannel to
+// keep this instance's L1 in sync with invalidations from other instances.
+func New(l2 *relevantcache.RedisCache, l1Size int, l1TTL time.Duration, opts ...Option) (*TieredCache, error) {
+	l1, err := lru.New(l1Size)
+	if err != nil {
+		return nil, err
+	}
+	c := &TieredCache{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.subscribe(ctx)
+	return c, nil
+}
+
+// Close stops the invalidation subscriber goroutine. The underlying L2
+// cache is left open since the caller owns it.
+func (c *TieredCache) Close() error {
+	c.cancel()
+	return nil
+}
+
+func (c *TieredCache) Get(item interface{}) ([]byte, error) {
+	key, err := relevantcache.ResolveKey(item)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := c.l1Get(key); ok {
+		return v, nil
+	}
+	data, err := c.l2.Get(item)
+	if err != nil {
+		return nil, err
+	}
+	c.l1Set(key, data)
+	return data, nil
+}
+
+func (c *TieredCache) Set(args ...interface{}) error {
+	if err := c.l2.Set(args...); err != nil {
+		return err
+	}
+	if key, value, ok := extractKeyValue(args...); ok {
+		c.l1Set(key, value)
+	}
+	return nil
+}
+
+// Del removes items and everything relevant to them from both tiers. l2.Del
+// publishes the resolved key set on its own invalidation channel, if one is
+// configured, so other instances' subscribe goroutines evict it from L1
+// too.
+func (c *TieredCache) Del(items ...interface{}) error {
+	keys := c.l2.RelevantKeys(items...)
+	if err := c.l2.Del(items...); err != nil {
+		return err
+	}
+	c.invalidateLocal(keys)
+	return nil
+}
+
+// Unlink behaves like Del; L1 has no async reclamation path worth
+// distinguishing, and L2's UNLINK already gives the non-blocking Redis side.
+func (c *TieredCache) Unlink(items ...interface{}) error {
+	keys := c.l2.RelevantKeys(items...)
+	if err := c.l2.Unlink(items...); err != nil {
+		return err
+	}
+	c.invalidateLocal(keys)
+	return nil
+}
+
+func (c *TieredCache) invalidateLocal(keys []string) {
+	for _, k := range keys {
+		c.l1.Remove(k)
+	}
+}
+
+// subscribeMinBackoff and subscribeMaxBackoff bound the delay subscribe
+// waits before re-subscribing after l2.Subscribe returns, e.g. because the
+// underlying pub/sub connection dropped.
+const (
+	subscribeMinBackoff = 100 * time.Millisecond
+	subscribeMaxBackoff = 5 * time.Second
+)
+
+// subscribe listens on l2's invalidation channel and keyspace notifications
+// for as long as the cache is open, evicting every key another instance (or
+// an external writer) resolved and published.
+//
+// l2.Subscribe returns as soon as its pub/sub connection drops (a Redis
+// restart, a network blip), with no reconnection of its own, so this loop
+// re-subscribes with a capped exponential backoff instead of leaving this
+// instance's L1 permanently cut off from invalidations after one transient
+// disconnect. Subscribe's error alone can't tell a genuine disconnect apart
+// from ctx being cancelled, so a subscription that stayed up for at least
+// subscribeMaxBackoff is treated as healthy and resets the backoff; one
+// that dies quickly backs off further before retrying.
+func (c *TieredCache) subscribe(ctx context.Context) {
+	backoff := subscribeMinBackoff
+	for {
+		start := time.Now()
+		_ = c.l2.Subscribe(ctx, func(keys []string) {
+			c.invalidateLocal(keys)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(start) >= subscribeMaxBackoff {
+			backoff = subscribeMinBackoff
+		} else {
+			backoff *= 2
+			if backoff > subscribeMaxBackoff {
+				backoff = subscribeMaxBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (c *TieredCache) l1Get(key string) ([]byte, bool) {
+	v, ok := c.l1.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(*l1Entry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.l1.Remove(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *TieredCache) l1Set(key string, value []byte) {
+	entry := &l1Entry{value: value}
+	if c.l1TTL > 0 {
+		entry.expires = time.Now().Add(c.l1TTL)
+	}
+	c.l1.Add(key, entry)
+}
+
+// extractKeyValue recovers the (key, value) pair Set was called with,
+// across all of its accepted argument shapes, so it can be mirrored into
+// L1. The meta header is stripped off the *Item case so L1 holds the same
+// plain payload Get returns.
+func extractKeyValue(args ...interface{}) (string, []byte, bool) {
+	switch len(args) {
+	case 1:
+		item, ok := args[0].(*relevantcache.Item)
+		if !ok {
+			return "", nil, false
+		}
+		key, encoded, _ := relevantcache.EncodeItem(item)
+		_, data := relevantcache.DecodeMeta(encoded)
+		return key, data, true
+	case 2, 3:
+		key, ok := args[0].(string)
+		if !ok {
+			return "", nil, false
+		}
+		switch v := args[1].(type) {
+		case []byte:
+			return key, v, true
+		case string:
+			return key, []byte(v), true
+		default:
+			return "", nil, false
+		}
+	default:
+		return "", nil, false
+	}
+}
+
+// Pass-through methods: these don't have tiered-cache semantics of their
+// own, so they go straight to L2.
+
+func (c *TieredCache) MGet(keys ...interface{}) ([][]byte, error) {
+	return c.l2.MGet(keys...)
+}
+
+func (c *TieredCache) HSet(key interface{}, field string, value interface{}) error {
+	return c.l2.HSet(key, field, value)
+}
+
+func (c *TieredCache) HGet(key interface{}, field string) ([]byte, error) {
+	return c.l2.HGet(key, field)
+}
+
+func (c *TieredCache) HLen(key interface{}) (int64, error) {
+	return c.l2.HLen(key)
+}
+
+func (c *TieredCache) Increment(key string) error {
+	return c.l2.Increment(key)
+}
+
+func (c *TieredCache) Purge() error {
+	c.l1.Purge()
+	return c.l2.Purge()
+}
+
+var _ relevantcache.Cache = (*TieredCache)(nil)
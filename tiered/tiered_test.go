@@ -0,0 +1,71 @@
+package tiered
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ysugimoto/relevantcache"
+)
+
+// TestTieredCacheDelPropagatesAcrossInstances exercises the race/ordering
+// condition the goroutine-driven L1/pub-sub sync exists to solve: a Del
+// issued against one TieredCache's L2 must evict the same key from a
+// second TieredCache's L1, via RedisCache.Subscribe rather than any ad-hoc
+// pub/sub of tiered's own. Requires a local Redis; skips if one isn't
+// reachable.
+func TestTieredCacheDelPropagatesAcrossInstances(t *testing.T) {
+	const channel = "tiered-test:invalidate"
+
+	l2A, err := relevantcache.NewRedisCache("redis://127.0.0.1:6379", relevantcache.WithInvalidationChannel(channel))
+	if err != nil {
+		t.Skipf("no local Redis available: %s", err)
+	}
+	defer l2A.Close()
+
+	l2B, err := relevantcache.NewRedisCache("redis://127.0.0.1:6379", relevantcache.WithInvalidationChannel(channel))
+	if err != nil {
+		t.Skipf("no local Redis available: %s", err)
+	}
+	defer l2B.Close()
+
+	cacheA, err := New(l2A, 128, 0)
+	if err != nil {
+		t.Fatalf("New cacheA: %s", err)
+	}
+	defer cacheA.Close()
+
+	cacheB, err := New(l2B, 128, 0)
+	if err != nil {
+		t.Fatalf("New cacheB: %s", err)
+	}
+	defer cacheB.Close()
+
+	key := "tiered-test:k1"
+	item := relevantcache.NewItem(key, []byte("hello"), 0, nil)
+	if err := cacheA.Set(item); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+
+	// Warm cacheB's L1 so a later stale read would come from L1, not L2,
+	// if the invalidation never arrived.
+	if _, err := cacheB.Get(key); err != nil {
+		t.Fatalf("Get (warm L1): %s", err)
+	}
+
+	if err := cacheA.Del(key); err != nil {
+		t.Fatalf("Del: %s", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := cacheB.Get(key)
+		if errors.Is(err, relevantcache.RedisNil) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cacheB still served %q after Del on cacheA; L1 invalidation never propagated", key)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}